@@ -0,0 +1,126 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/go-logr/logr"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func configMapInfo(namespace, name string) *resource.Info {
+	return &resource.Info{
+		Namespace: namespace,
+		Name:      name,
+		Mapping:   &apimeta.RESTMapping{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}},
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		}},
+	}
+}
+
+func TestSetOwnershipNamespacedResourceGetsOwnerReference(t *testing.T) {
+	owner := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.example.com/v1",
+		"kind":       "MyApp",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default", "uid": "abc-123"},
+	}}
+	expected := configMapInfo("default", "cm")
+
+	if err := setOwnership(expected, owner); err != nil {
+		t.Fatalf("setOwnership returned unexpected error: %v", err)
+	}
+
+	refs := expected.Object.(*unstructured.Unstructured).GetOwnerReferences()
+	if len(refs) != 1 {
+		t.Fatalf("got %d owner references, want 1", len(refs))
+	}
+	if refs[0].Name != "my-app" || refs[0].Kind != "MyApp" || refs[0].APIVersion != "apps.example.com/v1" {
+		t.Errorf("unexpected owner reference: %+v", refs[0])
+	}
+	if refs[0].Controller == nil || !*refs[0].Controller {
+		t.Error("owner reference should set Controller = true")
+	}
+
+	// Calling it again shouldn't duplicate the reference.
+	if err := setOwnership(expected, owner); err != nil {
+		t.Fatalf("second setOwnership call returned unexpected error: %v", err)
+	}
+	if got := len(expected.Object.(*unstructured.Unstructured).GetOwnerReferences()); got != 1 {
+		t.Errorf("got %d owner references after calling setOwnership twice, want 1", got)
+	}
+}
+
+func TestSetOwnershipClusterScopedResourceGetsFinalizerInstead(t *testing.T) {
+	owner := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.example.com/v1",
+		"kind":       "MyApp",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default", "uid": "abc-123"},
+	}}
+	expected := &resource.Info{
+		Namespace: "",
+		Name:      "my-cluster-role",
+		Mapping:   &apimeta.RESTMapping{GroupVersionKind: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}},
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "ClusterRole",
+			"metadata":   map[string]interface{}{"name": "my-cluster-role"},
+		}},
+	}
+
+	if err := setOwnership(expected, owner); err != nil {
+		t.Fatalf("setOwnership returned unexpected error: %v", err)
+	}
+
+	want := clusterScopedChildFinalizer(expected.Mapping.GroupVersionKind, expected.Name)
+	if !containsString(owner.GetFinalizers(), want) {
+		t.Errorf("owner finalizers = %v, want to contain %q", owner.GetFinalizers(), want)
+	}
+	// A cluster-scoped resource can't carry an ownerReference back to a
+	// namespaced CR, so the child itself should be untouched.
+	if refs := expected.Object.(*unstructured.Unstructured).GetOwnerReferences(); len(refs) != 0 {
+		t.Errorf("cluster-scoped child should have no owner references, got %+v", refs)
+	}
+}
+
+func TestOrphanedResourceKeysSkipsExpectedAndNeverDeleteGVKs(t *testing.T) {
+	stillInChart := resourceKey{gvk: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, namespace: "default", name: "keep"}
+	droppedFromChart := resourceKey{gvk: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, namespace: "default", name: "orphan"}
+	protectedPVC := resourceKey{gvk: schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}, namespace: "default", name: "data"}
+	protectedBySecret := resourceKey{gvk: schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, namespace: "default", name: "creds"}
+
+	originalInfosByKey := map[resourceKey]*resource.Info{
+		stillInChart:      {},
+		droppedFromChart:  {},
+		protectedPVC:      {},
+		protectedBySecret: {},
+	}
+	expectedKeys := map[resourceKey]bool{stillInChart: true}
+	neverDelete := []schema.GroupVersionKind{{Version: "v1", Kind: "Secret"}}
+
+	got := orphanedResourceKeys(originalInfosByKey, expectedKeys, neverDelete, logr.Discard())
+	sort.Slice(got, func(i, j int) bool { return got[i].name < got[j].name })
+
+	if len(got) != 1 || got[0] != droppedFromChart {
+		t.Errorf("orphanedResourceKeys() = %v, want [%v]", got, droppedFromChart)
+	}
+}
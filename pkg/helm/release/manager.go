@@ -21,24 +21,45 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
 	"helm.sh/helm/v3/pkg/action"
 	cpb "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/postrender"
 	rpb "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 
-	"github.com/mattbaird/jsonpatch"
 	"github.com/operator-framework/operator-sdk/pkg/helm/internal/types"
 )
 
+// Event reasons emitted on the owning custom resource as each release phase
+// makes progress. These are surfaced in `kubectl describe` so operators of
+// helm-based CRs can observe progress without reading controller logs.
+const (
+	EventReasonInstallError   = "InstallError"
+	EventReasonInstalled      = "Installed"
+	EventReasonUpdateError    = "UpdateError"
+	EventReasonUpdated        = "Updated"
+	EventReasonRolledBack     = "RolledBack"
+	EventReasonRollbackError  = "RollbackError"
+	EventReasonDriftCorrected = "DriftCorrected"
+	EventReasonPatchFailed    = "PatchFailed"
+	EventReasonUninstalled    = "Uninstalled"
+	EventReasonUninstallError = "UninstallError"
+)
+
 // Manager manages a Helm release. It can install, update, reconcile,
 // and uninstall a release.
 type Manager interface {
@@ -67,6 +88,76 @@ type manager struct {
 	isUpdateRequired bool
 	deployedRelease  *rpb.Release
 	chart            *cpb.Chart
+
+	// installOpts controls the "atomic install/upgrade" behavior described in
+	// https://helm.sh/docs/helm/helm_upgrade/#options. It is populated by the
+	// manager factory from CR annotations or manager-wide defaults.
+	installOpts InstallOptions
+
+	// log receives structured, phase-by-phase progress records. eventRecorder
+	// raises a corresponding Kubernetes Event against owner, the CR that owns
+	// this release, so that `kubectl describe` shows real progress.
+	log           logr.Logger
+	eventRecorder record.EventRecorder
+	owner         runtime.Object
+
+	// postRenderer, when set, is run over every manifest Helm renders for
+	// this release -- for the dry-run candidate in getCandidateRelease as
+	// well as the real install/upgrade -- so that reconcileRelease diffs
+	// against the same post-rendered manifest that was actually applied.
+	postRenderer postrender.PostRenderer
+
+	// setOwnerReferences controls whether ReconcileRelease stamps owner as a
+	// controller owner reference on namespaced resources (and records a
+	// finalizer on owner for cluster-scoped ones, which can't carry an
+	// ownerReference to a namespaced CR). ManagerFactory.NewManager defaults
+	// this to true.
+	setOwnerReferences bool
+
+	// neverDelete lists GVKs that ReconcileRelease's garbage-collection pass
+	// must never delete, even when a resource drops out of the chart
+	// between revisions, in addition to the package-wide
+	// defaultNeverDeleteGVKs (PVCs, Namespaces).
+	neverDelete []schema.GroupVersionKind
+}
+
+// event is a convenience wrapper around eventRecorder.Event that no-ops when
+// either the recorder or the owning object hasn't been configured, which
+// keeps the phase methods below readable without a nil-check at every
+// call site.
+func (m manager) event(eventType, reason, message string) {
+	emitEvent(m.eventRecorder, m.owner, eventType, reason, message)
+}
+
+func emitEvent(recorder record.EventRecorder, owner runtime.Object, eventType, reason, message string) {
+	if recorder == nil || owner == nil {
+		return
+	}
+	recorder.Event(owner, eventType, reason, message)
+}
+
+// InstallOptions configures the atomic-install/upgrade semantics that are
+// passed through to the underlying Helm install and upgrade actions.
+type InstallOptions struct {
+	// Atomic, if true, rolls back (update) or uninstalls (install) the
+	// release automatically if the operation does not succeed. When Atomic
+	// is set, Wait is implied.
+	Atomic bool
+
+	// Wait, if true, waits until all resources are in a ready state before
+	// marking the release as successful.
+	Wait bool
+
+	// Timeout bounds how long to wait for Kubernetes commands, including
+	// Wait and the atomic rollback/uninstall. A zero value uses the Helm
+	// default.
+	Timeout time.Duration
+
+	// CleanupOnFail, if true, allows deletion of new resources created
+	// during a rollback when the operation fails. This only applies to
+	// UpdateRelease -- `helm install` has no equivalent flag, so
+	// InstallRelease ignores it.
+	CleanupOnFail bool
 }
 
 // ReleaseName returns the name of the release.
@@ -123,6 +214,7 @@ func (m *manager) Sync(ctx context.Context) error {
 		m.isUpdateRequired = true
 	}
 
+	m.log.V(1).Info("synced release", "isInstalled", m.isInstalled, "isUpdateRequired", m.isUpdateRequired)
 	return nil
 }
 
@@ -146,6 +238,7 @@ func (m manager) getCandidateRelease(namespace, name string, chart *cpb.Chart,
 	upgrade := action.NewUpgrade(m.actionConfig)
 	upgrade.Namespace = namespace
 	upgrade.DryRun = true
+	upgrade.PostRenderer = m.postRenderer
 	return upgrade.Run(name, chart, values)
 }
 
@@ -154,28 +247,47 @@ func (m manager) InstallRelease(ctx context.Context) (*rpb.Release, error) {
 	install := action.NewInstall(m.actionConfig)
 	install.ReleaseName = m.releaseName
 	install.Namespace = m.namespace
-
-	installedRelease, err := install.Run(m.chart, m.values)
+	install.Atomic = m.installOpts.Atomic
+	install.Wait = m.installOpts.Wait || m.installOpts.Atomic
+	install.Timeout = m.installOpts.Timeout
+	// action.Install has no CleanupOnFail field -- `helm install` has no
+	// equivalent flag -- so m.installOpts.CleanupOnFail only takes effect
+	// in UpdateRelease.
+	install.PostRenderer = m.postRenderer
+
+	m.log.Info("installing release", "releaseName", m.releaseName, "namespace", m.namespace, "atomic", install.Atomic)
+	installedRelease, err := install.RunWithContext(ctx, m.chart, m.values)
 	if err != nil {
-		// Workaround for helm/helm#3338
-		if installedRelease != nil {
-			uninstall := action.NewUninstall(m.actionConfig)
-			_, uninstallErr := uninstall.Run(m.releaseName)
-
-			// In certain cases, InstallRelease will return a partial release in
-			// the response even when it doesn't record the release in its release
-			// store (e.g. when there is an error rendering the release manifest).
-			// In that case the rollback will fail with a not found error because
-			// there was nothing to rollback.
-			//
-			// Only log a message about a rollback failure if the failure was caused
-			// by something other than the release not being found.
-			if uninstallErr != nil && !notFoundErr(uninstallErr) {
-				return nil, fmt.Errorf("failed installation (%s) and failed rollback: %w", err, uninstallErr)
+		// When Atomic is set, Helm has already uninstalled the release on our
+		// behalf, so the manual workaround below would just race with it.
+		if !install.Atomic {
+			// Workaround for helm/helm#3338
+			if installedRelease != nil {
+				uninstall := action.NewUninstall(m.actionConfig)
+				_, uninstallErr := uninstall.Run(m.releaseName)
+
+				// In certain cases, InstallRelease will return a partial release in
+				// the response even when it doesn't record the release in its release
+				// store (e.g. when there is an error rendering the release manifest).
+				// In that case the rollback will fail with a not found error because
+				// there was nothing to rollback.
+				//
+				// Only log a message about a rollback failure if the failure was caused
+				// by something other than the release not being found.
+				if uninstallErr != nil && !notFoundErr(uninstallErr) {
+					m.log.Error(uninstallErr, "failed to roll back failed installation", "installError", err)
+					m.event(corev1.EventTypeWarning, EventReasonInstallError,
+						fmt.Sprintf("failed installation (%s) and failed rollback: %s", err, uninstallErr))
+					return nil, fmt.Errorf("failed installation (%s) and failed rollback: %w", err, uninstallErr)
+				}
 			}
 		}
+		m.log.Error(err, "failed to install release")
+		m.event(corev1.EventTypeWarning, EventReasonInstallError, fmt.Sprintf("failed to install release: %s", err))
 		return nil, fmt.Errorf("failed to install release: %w", err)
 	}
+	m.log.Info("installed release", "releaseName", m.releaseName, "releaseVersion", installedRelease.Version)
+	m.event(corev1.EventTypeNormal, EventReasonInstalled, fmt.Sprintf("installed release %q (version %d)", m.releaseName, installedRelease.Version))
 	return installedRelease, nil
 }
 
@@ -183,45 +295,106 @@ func (m manager) InstallRelease(ctx context.Context) (*rpb.Release, error) {
 func (m manager) UpdateRelease(ctx context.Context) (*rpb.Release, *rpb.Release, error) {
 	upgrade := action.NewUpgrade(m.actionConfig)
 	upgrade.Namespace = m.namespace
-
-	updatedRelease, err := upgrade.Run(m.releaseName, m.chart, m.values)
+	upgrade.Atomic = m.installOpts.Atomic
+	upgrade.Wait = m.installOpts.Wait || m.installOpts.Atomic
+	upgrade.Timeout = m.installOpts.Timeout
+	upgrade.CleanupOnFail = m.installOpts.CleanupOnFail
+	upgrade.PostRenderer = m.postRenderer
+
+	m.log.Info("updating release", "releaseName", m.releaseName, "atomic", upgrade.Atomic)
+	updatedRelease, err := upgrade.RunWithContext(ctx, m.releaseName, m.chart, m.values)
 	if err != nil {
-		// Workaround for helm/helm#3338
-		if updatedRelease != nil {
-			rollback := action.NewRollback(m.actionConfig)
-			rollback.Force = true
-
-			// As of Helm 2.13, if UpdateRelease returns a non-nil release, that
-			// means the release was also recorded in the release store.
-			// Therefore, we should perform the rollback when we have a non-nil
-			// release. Any rollback error here would be unexpected, so always
-			// log both the update and rollback errors.
-			rollbackErr := rollback.Run(m.releaseName)
-			if rollbackErr != nil {
-				return nil, nil, fmt.Errorf("failed update (%s) and failed rollback: %w", err, rollbackErr)
+		// When Atomic is set, Helm has already rolled the release back on our
+		// behalf, so the manual workaround below would just race with it.
+		if !upgrade.Atomic {
+			// Workaround for helm/helm#3338
+			if updatedRelease != nil {
+				rollback := action.NewRollback(m.actionConfig)
+				rollback.Force = true
+
+				// As of Helm 2.13, if UpdateRelease returns a non-nil release, that
+				// means the release was also recorded in the release store.
+				// Therefore, we should perform the rollback when we have a non-nil
+				// release. Any rollback error here would be unexpected, so always
+				// log both the update and rollback errors.
+				m.log.Info("rolling back failed update", "updateError", err)
+				rollbackErr := rollback.Run(m.releaseName)
+				if rollbackErr != nil {
+					m.log.Error(rollbackErr, "failed to roll back failed update", "updateError", err)
+					m.event(corev1.EventTypeWarning, EventReasonRollbackError,
+						fmt.Sprintf("failed update (%s) and failed rollback: %s", err, rollbackErr))
+					return nil, nil, fmt.Errorf("failed update (%s) and failed rollback: %w", err, rollbackErr)
+				}
+				m.event(corev1.EventTypeWarning, EventReasonRolledBack, fmt.Sprintf("rolled back failed update: %s", err))
 			}
 		}
+		m.log.Error(err, "failed to update release")
+		m.event(corev1.EventTypeWarning, EventReasonUpdateError, fmt.Sprintf("failed to update release: %s", err))
 		return nil, nil, fmt.Errorf("failed to update release: %w", err)
 	}
+	m.log.Info("updated release", "releaseName", m.releaseName, "releaseVersion", updatedRelease.Version)
+	m.event(corev1.EventTypeNormal, EventReasonUpdated, fmt.Sprintf("updated release %q to version %d", m.releaseName, updatedRelease.Version))
 	return m.deployedRelease, updatedRelease, err
 }
 
 // ReconcileRelease creates or patches resources as necessary to match the
-// deployed release's manifest.
+// deployed release's manifest, computing a three-way merge patch against
+// the previous revision's manifest so that fields the chart no longer sets
+// are removed and fields added by other controllers are preserved. It also
+// links every resource it creates back to m.owner and deletes resources
+// that were rendered by the previous revision but dropped from this one,
+// mirroring `helm upgrade`'s own garbage collection.
 func (m manager) ReconcileRelease(ctx context.Context) (*rpb.Release, error) {
-	err := reconcileRelease(ctx, m.kubeClient, m.deployedRelease.Manifest)
+	originalManifest, err := m.previousDeployedManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous deployed release: %w", err)
+	}
+	err = reconcileRelease(ctx, m.log, m.eventRecorder, m.owner, m.kubeClient, originalManifest,
+		m.deployedRelease.Manifest, m.setOwnerReferences, m.neverDelete)
 	return m.deployedRelease, err
 }
 
-func reconcileRelease(ctx context.Context, kubeClient kube.Interface, expectedManifest string) error {
+// previousDeployedManifest returns the manifest of the release revision
+// immediately prior to m.deployedRelease, or the empty string if this is
+// the first revision or the prior revision can no longer be found.
+func (m manager) previousDeployedManifest() (string, error) {
+	if m.deployedRelease == nil || m.deployedRelease.Version <= 1 {
+		return "", nil
+	}
+	previous, err := m.storageBackend.Get(m.releaseName, m.deployedRelease.Version-1)
+	if err != nil {
+		if notFoundErr(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return previous.Manifest, nil
+}
+
+func reconcileRelease(ctx context.Context, log logr.Logger, recorder record.EventRecorder, owner runtime.Object,
+	kubeClient kube.Interface, originalManifest, expectedManifest string, setOwnerReferences bool,
+	neverDelete []schema.GroupVersionKind) error {
 	expectedInfos, err := kubeClient.Build(bytes.NewBufferString(expectedManifest), false)
 	if err != nil {
 		return err
 	}
-	return expectedInfos.Visit(func(expected *resource.Info, err error) error {
+	log.Info("building resources from manifest", "resourceCount", len(expectedInfos))
+
+	originalInfosByKey, err := buildInfosByKey(kubeClient, originalManifest)
+	if err != nil {
+		return fmt.Errorf("failed to build original manifest: %w", err)
+	}
+
+	expectedKeys := make(map[resourceKey]bool, len(expectedInfos))
+
+	log.Info("checking resources for drift", "resourceCount", len(expectedInfos))
+	err = expectedInfos.Visit(func(expected *resource.Info, err error) error {
 		if err != nil {
 			return err
 		}
+		expectedKeys[infoKey(expected)] = true
+		resourceLog := log.WithValues("namespace", expected.Namespace, "name", expected.Name,
+			"kind", expected.Mapping.GroupVersionKind.Kind)
 
 		expectedClient := resource.NewClientWithOptions(expected.Client, func(r *rest.Request) {
 			*r = *r.Context(ctx)
@@ -230,66 +403,258 @@ func reconcileRelease(ctx context.Context, kubeClient kube.Interface, expectedMa
 
 		existing, err := helper.Get(expected.Namespace, expected.Name, false)
 		if apierrors.IsNotFound(err) {
+			if setOwnerReferences {
+				if err := setOwnership(expected, owner); err != nil {
+					return fmt.Errorf("failed to set ownership: %w", err)
+				}
+			}
 			if _, err := helper.Create(expected.Namespace, true, expected.Object,
 				&metav1.CreateOptions{}); err != nil {
+				resourceLog.Error(err, "failed to create resource")
+				emitEvent(recorder, owner, corev1.EventTypeWarning, EventReasonPatchFailed,
+					fmt.Sprintf("failed to create %s %s/%s: %s", expected.Mapping.GroupVersionKind.Kind, expected.Namespace, expected.Name, err))
 				return fmt.Errorf("create error: %s", err)
 			}
+			resourceLog.Info("created resource")
 			return nil
 		} else if err != nil {
 			return err
 		}
 
-		patch, err := generatePatch(existing, expected.Object)
+		// Resources created before this feature existed (or by a previous
+		// revision with owner-reference stamping disabled) also need to be
+		// linked to owner, not just ones we're creating for the first time.
+		if setOwnerReferences {
+			if err := setOwnership(expected, owner); err != nil {
+				return fmt.Errorf("failed to set ownership: %w", err)
+			}
+		}
+
+		existingJSON, err := json.Marshal(existing)
+		if err != nil {
+			return fmt.Errorf("failed to marshal existing object: %w", err)
+		}
+		modifiedJSON, err := json.Marshal(expected.Object)
 		if err != nil {
-			return fmt.Errorf("failed to marshal JSON patch: %w", err)
+			return fmt.Errorf("failed to marshal expected object: %w", err)
+		}
+
+		// The "original" document is the same resource as it appeared in the
+		// previously deployed release's manifest. If this resource wasn't
+		// rendered by the previous revision (e.g. it's new in this upgrade),
+		// fall back to the modified document so the three-way diff degrades
+		// to a plain two-way diff instead of deleting unrelated fields.
+		originalJSON := modifiedJSON
+		if original, ok := originalInfosByKey[infoKey(expected)]; ok {
+			if originalJSON, err = json.Marshal(original.Object); err != nil {
+				return fmt.Errorf("failed to marshal original object: %w", err)
+			}
 		}
 
-		if patch == nil {
+		patch, patchType, err := generateThreeWayPatch(expected.Mapping.GroupVersionKind, originalJSON, modifiedJSON, existingJSON)
+		if err != nil {
+			return fmt.Errorf("failed to generate patch: %w", err)
+		}
+
+		if len(patch) == 0 || isEmptyPatch(patch) {
 			return nil
 		}
 
-		_, err = helper.Patch(expected.Namespace, expected.Name, apitypes.JSONPatchType, patch, &metav1.PatchOptions{})
+		_, err = helper.Patch(expected.Namespace, expected.Name, patchType, patch, &metav1.PatchOptions{})
 		if err != nil {
+			resourceLog.Error(err, "failed to patch resource")
+			emitEvent(recorder, owner, corev1.EventTypeWarning, EventReasonPatchFailed,
+				fmt.Sprintf("failed to patch %s %s/%s: %s", expected.Mapping.GroupVersionKind.Kind, expected.Namespace, expected.Name, err))
 			return fmt.Errorf("patch error: %w", err)
 		}
+		resourceLog.Info("corrected drift", "patch", string(patch))
+		emitEvent(recorder, owner, corev1.EventTypeNormal, EventReasonDriftCorrected,
+			fmt.Sprintf("corrected drift on %s %s/%s", expected.Mapping.GroupVersionKind.Kind, expected.Namespace, expected.Name))
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	return deleteOrphanedResources(ctx, log, recorder, owner, originalInfosByKey, expectedKeys, neverDelete)
 }
 
-func generatePatch(existing, expected runtime.Object) ([]byte, error) {
-	existingJSON, err := json.Marshal(existing)
-	if err != nil {
-		return nil, err
+// orphanedResourceKeys returns the keys present in originalInfosByKey but
+// not in expectedKeys, minus anything protected by isNeverDeleteGVK --
+// i.e. the resources deleteOrphanedResources should delete. Split out from
+// deleteOrphanedResources so the diffing logic can be unit tested without a
+// live cluster.
+func orphanedResourceKeys(originalInfosByKey map[resourceKey]*resource.Info, expectedKeys map[resourceKey]bool,
+	neverDelete []schema.GroupVersionKind, log logr.Logger) []resourceKey {
+	var orphaned []resourceKey
+	for key := range originalInfosByKey {
+		if expectedKeys[key] {
+			continue
+		}
+		if isNeverDeleteGVK(key.gvk, neverDelete) {
+			log.Info("skipping delete of protected resource no longer in chart",
+				"namespace", key.namespace, "name", key.name, "kind", key.gvk.Kind)
+			continue
+		}
+		orphaned = append(orphaned, key)
 	}
-	expectedJSON, err := json.Marshal(expected)
+	return orphaned
+}
+
+// deleteOrphanedResources removes resources that appeared in the previous
+// revision's manifest but were dropped from the current one, bringing
+// reconcileRelease's behavior in line with `helm upgrade`. Resources whose
+// GVK is in neverDelete (in addition to the package-wide defaultNeverDeleteGVKs)
+// are left alone even if the chart no longer renders them.
+func deleteOrphanedResources(ctx context.Context, log logr.Logger, recorder record.EventRecorder, owner runtime.Object,
+	originalInfosByKey map[resourceKey]*resource.Info, expectedKeys map[resourceKey]bool, neverDelete []schema.GroupVersionKind) error {
+	for _, key := range orphanedResourceKeys(originalInfosByKey, expectedKeys, neverDelete, log) {
+		original := originalInfosByKey[key]
+		resourceLog := log.WithValues("namespace", key.namespace, "name", key.name, "kind", key.gvk.Kind)
+		originalClient := resource.NewClientWithOptions(original.Client, func(r *rest.Request) {
+			*r = *r.Context(ctx)
+		})
+		helper := resource.NewHelper(originalClient, original.Mapping)
+		if _, err := helper.Delete(key.namespace, key.name); err != nil && !apierrors.IsNotFound(err) {
+			resourceLog.Error(err, "failed to delete orphaned resource")
+			emitEvent(recorder, owner, corev1.EventTypeWarning, EventReasonPatchFailed,
+				fmt.Sprintf("failed to delete orphaned %s %s/%s: %s", key.gvk.Kind, key.namespace, key.name, err))
+			return fmt.Errorf("delete error: %w", err)
+		}
+		resourceLog.Info("deleted orphaned resource")
+		emitEvent(recorder, owner, corev1.EventTypeNormal, EventReasonDriftCorrected,
+			fmt.Sprintf("deleted orphaned %s %s/%s", key.gvk.Kind, key.namespace, key.name))
+	}
+	return nil
+}
+
+// buildInfosByKey builds the resources in manifest (if any) into a lookup
+// keyed by GVK/namespace/name so reconcileRelease can find the revision of
+// a resource that matches a given candidate resource.
+func buildInfosByKey(kubeClient kube.Interface, manifest string) (map[resourceKey]*resource.Info, error) {
+	infosByKey := map[resourceKey]*resource.Info{}
+	if strings.TrimSpace(manifest) == "" {
+		return infosByKey, nil
+	}
+	infos, err := kubeClient.Build(bytes.NewBufferString(manifest), false)
 	if err != nil {
 		return nil, err
 	}
+	err = infos.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		infosByKey[infoKey(info)] = info
+		return nil
+	})
+	return infosByKey, err
+}
+
+type resourceKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+func infoKey(info *resource.Info) resourceKey {
+	return resourceKey{
+		gvk:       info.Mapping.GroupVersionKind,
+		namespace: info.Namespace,
+		name:      info.Name,
+	}
+}
+
+func isEmptyPatch(patch []byte) bool {
+	trimmed := strings.TrimSpace(string(patch))
+	return trimmed == "" || trimmed == "{}" || trimmed == "null"
+}
+
+// defaultNeverDeleteGVKs are resources reconcileRelease's garbage-collection
+// pass never deletes even when they drop out of the chart between
+// revisions, because that's rarely what a user wants and can be
+// destructive (PVC data, an entire Namespace). Extend this set per-CR via
+// the manager's neverDelete option.
+var defaultNeverDeleteGVKs = []schema.GroupVersionKind{
+	corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"),
+	corev1.SchemeGroupVersion.WithKind("Namespace"),
+}
+
+func isNeverDeleteGVK(gvk schema.GroupVersionKind, extra []schema.GroupVersionKind) bool {
+	for _, never := range defaultNeverDeleteGVKs {
+		if gvk == never {
+			return true
+		}
+	}
+	for _, never := range extra {
+		if gvk == never {
+			return true
+		}
+	}
+	return false
+}
 
-	ops, err := jsonpatch.CreatePatch(existingJSON, expectedJSON)
+// setOwnership links expected back to owner so that deleting the CR garbage
+// collects what it created. Namespaced resources get owner stamped as a
+// controller ownerReference. A namespaced CR cannot own a cluster-scoped
+// resource that way -- the apiserver rejects the ownerReference -- so for
+// those we instead record a finalizer naming the resource on owner itself;
+// it's up to the caller that persists owner (the reconciler's status
+// update) to honor it by deleting the tracked resource before letting the
+// finalizer be removed.
+func setOwnership(expected *resource.Info, owner runtime.Object) error {
+	if owner == nil {
+		return nil
+	}
+	ownerAccessor, err := apimeta.Accessor(owner)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to get owner accessor: %w", err)
 	}
 
-	// We ignore the "remove" operations from the full patch because they are
-	// fields added by Kubernetes or by the user after the existing release
-	// resource has been applied. The goal for this patch is to make sure that
-	// the fields managed by the Helm chart are applied.
-	patchOps := make([]jsonpatch.JsonPatchOperation, 0)
-	for _, op := range ops {
-		if op.Operation != "remove" {
-			patchOps = append(patchOps, op)
+	if expected.Namespace == "" {
+		finalizer := clusterScopedChildFinalizer(expected.Mapping.GroupVersionKind, expected.Name)
+		if !containsString(ownerAccessor.GetFinalizers(), finalizer) {
+			ownerAccessor.SetFinalizers(append(ownerAccessor.GetFinalizers(), finalizer))
 		}
+		return nil
 	}
 
-	// If there are no patch operations, return nil. Callers are expected
-	// to check for a nil response and skip the patch operation to avoid
-	// unnecessary chatter with the API server.
-	if len(patchOps) == 0 {
-		return nil, nil
+	childAccessor, err := apimeta.Accessor(expected.Object)
+	if err != nil {
+		return fmt.Errorf("failed to get accessor for %s %s/%s: %w",
+			expected.Mapping.GroupVersionKind.Kind, expected.Namespace, expected.Name, err)
 	}
+	for _, ref := range childAccessor.GetOwnerReferences() {
+		if ref.UID == ownerAccessor.GetUID() {
+			return nil
+		}
+	}
+	ownerGVK := owner.GetObjectKind().GroupVersionKind()
+	blockOwnerDeletion, controller := true, true
+	childAccessor.SetOwnerReferences(append(childAccessor.GetOwnerReferences(), metav1.OwnerReference{
+		APIVersion:         ownerGVK.GroupVersion().String(),
+		Kind:               ownerGVK.Kind,
+		Name:               ownerAccessor.GetName(),
+		UID:                ownerAccessor.GetUID(),
+		Controller:         &controller,
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}))
+	return nil
+}
 
-	return json.Marshal(patchOps)
+// clusterScopedChildFinalizer names the finalizer setOwnership records on
+// the owning CR for a cluster-scoped resource it created, since such a
+// resource can't carry an ownerReference back to a namespaced CR.
+func clusterScopedChildFinalizer(gvk schema.GroupVersionKind, name string) string {
+	return fmt.Sprintf("helm.operator-sdk/%s.%s", strings.ToLower(gvk.Kind), name)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
 }
 
 // UninstallRelease performs a Helm release uninstall.
@@ -306,7 +671,15 @@ func (m manager) UninstallRelease(ctx context.Context) (*rpb.Release, error) {
 		return nil, driver.ErrReleaseNotFound
 	}
 
+	m.log.Info("uninstalling release", "releaseName", m.releaseName)
 	uninstall := action.NewUninstall(m.actionConfig)
 	uninstallResponse, err := uninstall.Run(m.releaseName)
-	return uninstallResponse.Release, err
+	if err != nil {
+		m.log.Error(err, "failed to uninstall release")
+		m.event(corev1.EventTypeWarning, EventReasonUninstallError, fmt.Sprintf("failed to uninstall release: %s", err))
+		return nil, err
+	}
+	m.log.Info("uninstalled release", "releaseName", m.releaseName, "hookCount", len(uninstallResponse.Release.Hooks))
+	m.event(corev1.EventTypeNormal, EventReasonUninstalled, fmt.Sprintf("uninstalled release %q", m.releaseName))
+	return uninstallResponse.Release, nil
 }
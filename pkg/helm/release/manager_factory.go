@@ -0,0 +1,307 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	cpb "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/storage"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+// Annotations on the custom resource that let users opt a single release
+// into atomic install/upgrade semantics without changing manager-wide
+// defaults.
+const (
+	helmAtomicAnnotation        = "helm.operator-sdk/atomic"
+	helmWaitAnnotation          = "helm.operator-sdk/wait"
+	helmTimeoutAnnotation       = "helm.operator-sdk/timeout"
+	helmCleanupOnFailAnnotation = "helm.operator-sdk/cleanup-on-fail"
+)
+
+// Annotations controlling ReconcileRelease's ownership and garbage
+// collection behavior for a single CR.
+const (
+	helmSetOwnerReferencesAnnotation = "helm.operator-sdk/set-owner-references"
+	helmNeverDeleteAnnotation        = "helm.operator-sdk/never-delete"
+)
+
+// InstallOptionsDefaults is the zero InstallOptions. NewManager does not
+// read it; the defaults it applies come from ManagerFactory.InstallOptions.
+var InstallOptionsDefaults = InstallOptions{}
+
+// ManagerFactory builds the Manager for a single Helm release, wiring the
+// manager-wide defaults below together with any per-CR annotation overrides
+// (storage driver, atomic install/upgrade, post-renderer, owner-reference
+// and garbage-collection behavior) into the manager it constructs.
+type ManagerFactory struct {
+	// ActionConfig is the Helm action configuration NewManager uses to
+	// drive install, upgrade, reconcile, and uninstall actions. Its Log
+	// field is overwritten by NewManager to route through Log.
+	ActionConfig *action.Configuration
+
+	// Clientset backs the secret/configmap storage drivers, which keep
+	// release history in the namespace passed to NewManager. NewManager
+	// does not read Namespace.
+	Clientset kubernetes.Interface
+	Namespace string
+
+	// KubeClient builds and applies the manifests ReconcileRelease diffs
+	// against.
+	KubeClient kube.Interface
+
+	// Log receives structured, phase-by-phase progress records from every
+	// Manager this factory builds, and is also wired into ActionConfig.Log.
+	Log logr.Logger
+
+	// EventRecorder raises Kubernetes Events against the CR each Manager's
+	// release is owned by.
+	EventRecorder record.EventRecorder
+
+	// StorageDriver is the manager-wide default release-history backend,
+	// overridable per-CR via HelmStorageDriverAnnotation.
+	StorageDriver StorageDriver
+
+	// StorageDriverDSN is the DSN used when StorageDriver (or a per-CR
+	// override) selects StorageDriverSQL.
+	StorageDriverDSN string
+
+	// InstallOptions are the manager-wide atomic-install/upgrade defaults,
+	// overridable per-CR via the helmAtomicAnnotation family.
+	InstallOptions InstallOptions
+
+	// PostRenderer is the manager-wide default post-renderer, overridable
+	// per-CR via helmPostRendererAnnotation.
+	PostRenderer postrender.PostRenderer
+
+	// SetOwnerReferences is the manager-wide default for ReconcileRelease's
+	// owner-reference/GC behavior, overridable per-CR via
+	// helmSetOwnerReferencesAnnotation. A nil value defaults to true.
+	SetOwnerReferences *bool
+
+	// NeverDelete is the manager-wide default list of GVKs ReconcileRelease
+	// must never delete, in addition to defaultNeverDeleteGVKs, extensible
+	// per-CR via helmNeverDeleteAnnotation.
+	NeverDelete []schema.GroupVersionKind
+}
+
+// NewManager builds the Manager that owns the Helm release rendered from
+// chart and values under releaseName/namespace for the CR r, applying any
+// per-CR annotation overrides of f's manager-wide defaults.
+func (f ManagerFactory) NewManager(r *unstructured.Unstructured, releaseName, namespace string,
+	chart *cpb.Chart, values map[string]interface{}) (Manager, error) {
+	setActionConfigLog(f.ActionConfig, f.Log)
+
+	logFn := func(format string, v ...interface{}) {
+		f.Log.V(1).Info(fmt.Sprintf(format, v...))
+	}
+	storageBackend, err := storageBackendForCR(r, f.Clientset, namespace, f.StorageDriverDSN, f.StorageDriver, logFn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct storage backend: %w", err)
+	}
+
+	postRenderer, err := postRendererForCR(r, f.PostRenderer)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultSetOwnerReferences := true
+	if f.SetOwnerReferences != nil {
+		defaultSetOwnerReferences = *f.SetOwnerReferences
+	}
+	setOwnerReferences, neverDelete, err := gcOptionsForCR(r, defaultSetOwnerReferences, f.NeverDelete)
+	if err != nil {
+		return nil, err
+	}
+
+	var owner runtime.Object
+	if r != nil {
+		owner = r
+	}
+
+	return &manager{
+		actionConfig:       f.ActionConfig,
+		storageBackend:     storageBackend,
+		kubeClient:         f.KubeClient,
+		releaseName:        releaseName,
+		namespace:          namespace,
+		values:             values,
+		chart:              chart,
+		installOpts:        installOptionsForCR(r, f.InstallOptions),
+		log:                f.Log,
+		eventRecorder:      f.EventRecorder,
+		owner:              owner,
+		postRenderer:       postRenderer,
+		setOwnerReferences: setOwnerReferences,
+		neverDelete:        neverDelete,
+	}, nil
+}
+
+// installOptionsForCR merges defaults with any per-CR
+// annotation overrides, returning the InstallOptions to use for this
+// release's install/upgrade.
+func installOptionsForCR(r *unstructured.Unstructured, defaults InstallOptions) InstallOptions {
+	opts := defaults
+	if r == nil {
+		return opts
+	}
+	annotations := r.GetAnnotations()
+
+	if v, ok := annotations[helmAtomicAnnotation]; ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			opts.Atomic = b
+		}
+	}
+	if v, ok := annotations[helmWaitAnnotation]; ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			opts.Wait = b
+		}
+	}
+	if v, ok := annotations[helmTimeoutAnnotation]; ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			opts.Timeout = d
+		}
+	}
+	if v, ok := annotations[helmCleanupOnFailAnnotation]; ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			opts.CleanupOnFail = b
+		}
+	}
+	return opts
+}
+
+// gcOptionsForCR merges the manager-wide defaults for owner-reference
+// stamping and the delete-protected GVK list with any per-CR annotation
+// overrides. helmNeverDeleteAnnotation adds to, rather than replaces,
+// defaultNeverDelete.
+func gcOptionsForCR(r *unstructured.Unstructured, defaultSetOwnerReferences bool,
+	defaultNeverDelete []schema.GroupVersionKind) (setOwnerReferences bool, neverDelete []schema.GroupVersionKind, err error) {
+	setOwnerReferences = defaultSetOwnerReferences
+	neverDelete = defaultNeverDelete
+	if r == nil {
+		return setOwnerReferences, neverDelete, nil
+	}
+	annotations := r.GetAnnotations()
+
+	if v, ok := annotations[helmSetOwnerReferencesAnnotation]; ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			setOwnerReferences = b
+		}
+	}
+	if v, ok := annotations[helmNeverDeleteAnnotation]; ok && v != "" {
+		extra, err := parseGVKList(v)
+		if err != nil {
+			return setOwnerReferences, neverDelete, fmt.Errorf("invalid %s annotation: %w", helmNeverDeleteAnnotation, err)
+		}
+		neverDelete = append(append([]schema.GroupVersionKind{}, defaultNeverDelete...), extra...)
+	}
+	return setOwnerReferences, neverDelete, nil
+}
+
+// parseGVKList parses a semicolon-separated list of GVKs, each in the
+// "group/version, Kind=Kind" format parseGVK accepts, e.g.
+// "v1, Kind=PersistentVolumeClaim;apps/v1, Kind=StatefulSet".
+func parseGVKList(v string) ([]schema.GroupVersionKind, error) {
+	var gvks []schema.GroupVersionKind
+	for _, entry := range strings.Split(v, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		gvk, err := parseGVK(entry)
+		if err != nil {
+			return nil, err
+		}
+		gvks = append(gvks, gvk)
+	}
+	return gvks, nil
+}
+
+// parseGVK parses a single GVK in the "group/version, Kind=Kind" format
+// produced by schema.GroupVersionKind.String(). Core-group GVKs may be
+// written with or without the leading slash that String() produces, e.g.
+// "/v1, Kind=Namespace" or "v1, Kind=Namespace".
+func parseGVK(s string) (schema.GroupVersionKind, error) {
+	const sep = ", Kind="
+	i := strings.Index(s, sep)
+	if i < 0 {
+		return schema.GroupVersionKind{}, fmt.Errorf("invalid GVK %q: expected \"group/version, Kind=Kind\"", s)
+	}
+	gv, err := schema.ParseGroupVersion(s[:i])
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("invalid GVK %q: %w", s, err)
+	}
+	kind := s[i+len(sep):]
+	if kind == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf("invalid GVK %q: empty kind", s)
+	}
+	return gv.WithKind(kind), nil
+}
+
+// storageBackendForCR selects the storage.Storage to use for r, honoring a
+// per-CR HelmStorageDriverAnnotation override of defaultDriver, and
+// constructs it against clientset/namespace (and sqlDSN, for the SQL
+// driver).
+func storageBackendForCR(r *unstructured.Unstructured, clientset kubernetes.Interface, namespace, sqlDSN string,
+	defaultDriver StorageDriver, logFn func(string, ...interface{})) (*storage.Storage, error) {
+	var annotations map[string]string
+	if r != nil {
+		annotations = r.GetAnnotations()
+	}
+	driverType := storageDriverForCR(annotations, defaultDriver)
+	return NewStorageBackend(driverType, clientset, namespace, sqlDSN, logFn)
+}
+
+// postRendererForCR returns the exec-based postrender.PostRenderer the CR's
+// helmPostRendererAnnotation requests, or defaultPostRenderer (which may be
+// a programmatically-registered PostRendererFunc, or nil) if the CR has no
+// such annotation.
+func postRendererForCR(r *unstructured.Unstructured, defaultPostRenderer postrender.PostRenderer) (postrender.PostRenderer, error) {
+	if r == nil {
+		return defaultPostRenderer, nil
+	}
+	bin, ok := r.GetAnnotations()[helmPostRendererAnnotation]
+	if !ok || bin == "" {
+		return defaultPostRenderer, nil
+	}
+	pr, err := postrender.NewExec(bin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct post-renderer %q: %w", bin, err)
+	}
+	return pr, nil
+}
+
+// setActionConfigLog wires the Helm action configuration's own progress log
+// -- the one printed by `helm install --debug` -- through to the manager's
+// structured logger, at debug (V(1)) verbosity, so that Helm-internal
+// messages (e.g. "Starting delete for ... hook") show up alongside the
+// manager's own phase logs instead of going to stdout.
+func setActionConfigLog(actionConfig *action.Configuration, log logr.Logger) {
+	actionConfig.Log = func(format string, v ...interface{}) {
+		log.V(1).Info(fmt.Sprintf(format, v...))
+	}
+}
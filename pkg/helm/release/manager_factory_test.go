@@ -0,0 +1,91 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestManagerFactoryNewManagerAppliesAnnotationOverrides(t *testing.T) {
+	r := &unstructured.Unstructured{}
+	r.SetName("my-release")
+	r.SetNamespace("my-namespace")
+	r.SetAnnotations(map[string]string{
+		helmAtomicAnnotation:             "true",
+		helmSetOwnerReferencesAnnotation: "false",
+		HelmStorageDriverAnnotation:      string(StorageDriverMemory),
+	})
+
+	f := ManagerFactory{
+		ActionConfig:  &action.Configuration{},
+		Clientset:     fake.NewSimpleClientset(),
+		Namespace:     "my-namespace",
+		Log:           logr.Discard(),
+		EventRecorder: record.NewFakeRecorder(10),
+		StorageDriver: StorageDriverSecret,
+	}
+
+	mgr, err := f.NewManager(r, "my-release", "my-namespace", nil, nil)
+	if err != nil {
+		t.Fatalf("NewManager returned unexpected error: %v", err)
+	}
+
+	m, ok := mgr.(*manager)
+	if !ok {
+		t.Fatalf("NewManager returned %T, expected *manager", mgr)
+	}
+	if !m.installOpts.Atomic {
+		t.Error("helm.operator-sdk/atomic=true annotation was not applied to installOpts.Atomic")
+	}
+	if m.setOwnerReferences {
+		t.Error("helm.operator-sdk/set-owner-references=false annotation was not applied to setOwnerReferences")
+	}
+	if m.owner == nil {
+		t.Error("NewManager did not set owner to the CR")
+	}
+	if m.storageBackend == nil {
+		t.Error("NewManager did not construct a storage backend")
+	}
+	if m.log != f.Log {
+		t.Error("NewManager did not wire the factory's Log into the manager")
+	}
+}
+
+func TestManagerFactoryNewManagerDefaultsSetOwnerReferencesToTrue(t *testing.T) {
+	f := ManagerFactory{
+		ActionConfig:  &action.Configuration{},
+		Clientset:     fake.NewSimpleClientset(),
+		Namespace:     "my-namespace",
+		Log:           logr.Discard(),
+		EventRecorder: record.NewFakeRecorder(10),
+		StorageDriver: StorageDriverMemory,
+	}
+
+	mgr, err := f.NewManager(nil, "my-release", "my-namespace", nil, nil)
+	if err != nil {
+		t.Fatalf("NewManager returned unexpected error: %v", err)
+	}
+
+	m := mgr.(*manager)
+	if !m.setOwnerReferences {
+		t.Error("NewManager should default setOwnerReferences to true when ManagerFactory.SetOwnerReferences is unset")
+	}
+}
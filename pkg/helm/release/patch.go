@@ -0,0 +1,66 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// generateThreeWayPatch computes the patch required to bring the live
+// "current" object in line with "modified" (the object rendered by the
+// candidate release) while preserving fields that were added to "current"
+// outside of the chart, using "original" (the same resource as it appeared
+// in the previously deployed release's manifest) to distinguish "the chart
+// used to set this field and no longer does" from "something else owns
+// this field". This mirrors how `kubectl apply` computes its patches.
+//
+// For GVKs with a registered, versioned Go type we use a strategic merge
+// patch so that list fields with a patch-merge-key (e.g. container ports)
+// are merged by key instead of by index. For everything else -- CRDs and
+// other types with no registered Go struct -- we fall back to an RFC 7386
+// three-way JSON merge patch.
+func generateThreeWayPatch(gvk schema.GroupVersionKind, original, modified, current []byte) ([]byte, apitypes.PatchType, error) {
+	versionedObject, err := scheme.Scheme.New(gvk)
+	switch {
+	case runtime.IsNotRegisteredError(err):
+		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create three-way JSON merge patch: %w", err)
+		}
+		return patch, apitypes.MergePatchType, nil
+	case err != nil:
+		return nil, "", err
+	default:
+		lookupPatchMeta, err := strategicpatch.NewPatchMetaFromStruct(versionedObject)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to look up patch metadata for %s: %w", gvk, err)
+		}
+		// overwrite=true: reconcileRelease's whole point is to correct
+		// drift, so a field that diverges between original and current
+		// should be overwritten with modified's value rather than erroring.
+		patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, lookupPatchMeta, true)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create three-way strategic merge patch: %w", err)
+		}
+		return patch, apitypes.StrategicMergePatchType, nil
+	}
+}
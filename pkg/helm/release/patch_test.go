@@ -0,0 +1,120 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+)
+
+func TestGenerateThreeWayPatchRegisteredGVKUsesStrategicMerge(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+	// original: the same ConfigMap as rendered by the previously deployed
+	// release -- the chart used to set "baz" and no longer does.
+	original := []byte(`{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": {"name": "cm", "namespace": "default"},
+		"data": {"foo": "bar", "baz": "qux"}
+	}`)
+	// modified: the candidate release's manifest.
+	modified := []byte(`{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": {"name": "cm", "namespace": "default"},
+		"data": {"foo": "bar2"}
+	}`)
+	// current: the live object, with an annotation some other controller
+	// added that the chart has never known about.
+	current := []byte(`{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": {"name": "cm", "namespace": "default", "annotations": {"other-controller": "keep-me"}},
+		"data": {"foo": "bar", "baz": "qux"}
+	}`)
+
+	patch, patchType, err := generateThreeWayPatch(gvk, original, modified, current)
+	if err != nil {
+		t.Fatalf("generateThreeWayPatch returned unexpected error: %v", err)
+	}
+	if patchType != apitypes.StrategicMergePatchType {
+		t.Errorf("patchType = %v, want %v", patchType, apitypes.StrategicMergePatchType)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(patch, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal patch %s: %v", patch, err)
+	}
+	data, ok := decoded["data"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("patch %s has no \"data\" field", patch)
+	}
+	if data["foo"] != "bar2" {
+		t.Errorf("patch data.foo = %v, want \"bar2\"", data["foo"])
+	}
+	if data["baz"] != nil {
+		t.Errorf("patch data.baz = %v, want nil (removed)", data["baz"])
+	}
+	if _, ok := decoded["metadata"]; ok {
+		t.Errorf("patch %s touches metadata/annotations, which neither original nor modified changed", patch)
+	}
+}
+
+func TestGenerateThreeWayPatchUnregisteredGVKFallsBackToJSONMergePatch(t *testing.T) {
+	// No Go type is registered for custom.example.com/v1 Widget, so this
+	// must fall back to the RFC 7386 three-way JSON merge patch instead of
+	// a strategic merge patch.
+	gvk := schema.GroupVersionKind{Group: "custom.example.com", Version: "v1", Kind: "Widget"}
+
+	original := []byte(`{
+		"apiVersion": "custom.example.com/v1", "kind": "Widget",
+		"metadata": {"name": "w", "namespace": "default"},
+		"spec": {"size": "small", "color": "red"}
+	}`)
+	modified := []byte(`{
+		"apiVersion": "custom.example.com/v1", "kind": "Widget",
+		"metadata": {"name": "w", "namespace": "default"},
+		"spec": {"size": "large"}
+	}`)
+	current := []byte(`{
+		"apiVersion": "custom.example.com/v1", "kind": "Widget",
+		"metadata": {"name": "w", "namespace": "default"},
+		"spec": {"size": "small", "color": "red"}
+	}`)
+
+	patch, patchType, err := generateThreeWayPatch(gvk, original, modified, current)
+	if err != nil {
+		t.Fatalf("generateThreeWayPatch returned unexpected error: %v", err)
+	}
+	if patchType != apitypes.MergePatchType {
+		t.Errorf("patchType = %v, want %v", patchType, apitypes.MergePatchType)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(patch, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal patch %s: %v", patch, err)
+	}
+	spec, ok := decoded["spec"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("patch %s has no \"spec\" field", patch)
+	}
+	if spec["size"] != "large" {
+		t.Errorf("patch spec.size = %v, want \"large\"", spec["size"])
+	}
+	if spec["color"] != nil {
+		t.Errorf("patch spec.color = %v, want nil (removed)", spec["color"])
+	}
+}
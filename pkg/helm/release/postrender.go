@@ -0,0 +1,40 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+
+	"helm.sh/helm/v3/pkg/postrender"
+)
+
+// helmPostRendererAnnotation names an exec-based post-renderer (a binary or
+// script on the operator's PATH) to pipe the rendered manifest through
+// before it is installed/upgraded and diffed, e.g. to inject istio-proxy,
+// stamp org-wide labels, or apply Kyverno-style mutations without forking
+// the chart.
+const helmPostRendererAnnotation = "helm.operator-sdk/post-renderer"
+
+// PostRendererFunc adapts a plain Go function to the postrender.PostRenderer
+// interface Helm's install/upgrade actions expect, for operators that would
+// rather register a callback programmatically than shell out to a binary.
+type PostRendererFunc func(renderedManifests *bytes.Buffer) (*bytes.Buffer, error)
+
+// Run implements postrender.PostRenderer.
+func (f PostRendererFunc) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return f(renderedManifests)
+}
+
+var _ postrender.PostRenderer = PostRendererFunc(nil)
@@ -0,0 +1,115 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"fmt"
+	"os"
+
+	// The Postgres driver registers itself with database/sql under the
+	// "postgres" name; it is only ever referenced for its side effect.
+	_ "github.com/lib/pq"
+
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HelmStorageDriverEnvVar and HelmStorageDriverDSNEnvVar name environment
+// variables for a cluster-wide default storage driver and SQL DSN.
+// DefaultStorageDriver reads HelmStorageDriverEnvVar; nothing in this
+// package reads HelmStorageDriverDSNEnvVar, and no command-line flags are
+// defined for either.
+const (
+	HelmStorageDriverEnvVar    = "HELM_STORAGE_DRIVER"
+	HelmStorageDriverDSNEnvVar = "HELM_STORAGE_DRIVER_DSN"
+)
+
+// DefaultStorageDriver returns the manager-wide default storage driver,
+// taken from HelmStorageDriverEnvVar, falling back to StorageDriverSecret.
+func DefaultStorageDriver() StorageDriver {
+	if v := os.Getenv(HelmStorageDriverEnvVar); v != "" {
+		return StorageDriver(v)
+	}
+	return StorageDriverSecret
+}
+
+// StorageDriver identifies where a manager's release history is persisted.
+type StorageDriver string
+
+const (
+	// StorageDriverSecret stores release history as Kubernetes Secrets. This
+	// is Helm's own default and remains the operator's default.
+	StorageDriverSecret StorageDriver = "secret"
+
+	// StorageDriverConfigMap stores release history as Kubernetes ConfigMaps.
+	StorageDriverConfigMap StorageDriver = "configmap"
+
+	// StorageDriverMemory keeps release history only in-process. It does not
+	// survive a restart and is intended for tests.
+	StorageDriverMemory StorageDriver = "memory"
+
+	// StorageDriverSQL stores release history in an external SQL database,
+	// currently Postgres, addressed by a DSN. This avoids the per-Secret
+	// size limit and gives multi-replica operator deployments a single
+	// shared source of truth for release state.
+	StorageDriverSQL StorageDriver = "sql"
+)
+
+// HelmStorageDriverAnnotation lets an individual CR select a storage driver
+// that differs from the manager-wide default, e.g. because its release
+// history is too large for a Secret.
+const HelmStorageDriverAnnotation = "helm.operator-sdk/storage-driver"
+
+// NewStorageBackend constructs the storage.Storage for driverType. ns and
+// clientset are used for the secret/configmap drivers; sqlDSN is required
+// for StorageDriverSQL and ignored otherwise.
+func NewStorageBackend(driverType StorageDriver, clientset kubernetes.Interface, ns, sqlDSN string, logFn func(string, ...interface{})) (*storage.Storage, error) {
+	switch driverType {
+	case "", StorageDriverSecret:
+		d := driver.NewSecrets(clientset.CoreV1().Secrets(ns))
+		d.Log = logFn
+		return storage.Init(d), nil
+	case StorageDriverConfigMap:
+		d := driver.NewConfigMaps(clientset.CoreV1().ConfigMaps(ns))
+		d.Log = logFn
+		return storage.Init(d), nil
+	case StorageDriverMemory:
+		d := driver.NewMemory()
+		return storage.Init(d), nil
+	case StorageDriverSQL:
+		if sqlDSN == "" {
+			return nil, fmt.Errorf("storage driver %q requires a DSN", StorageDriverSQL)
+		}
+		// NewSQL opens its own connection from sqlDSN; it doesn't take a
+		// pre-opened *sql.DB.
+		d, err := driver.NewSQL(sqlDSN, logFn, ns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize SQL storage driver: %w", err)
+		}
+		return storage.Init(d), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %q", driverType)
+	}
+}
+
+// storageDriverForCR returns the StorageDriver the given CR's annotations
+// request, or defaultDriver if it has none.
+func storageDriverForCR(annotations map[string]string, defaultDriver StorageDriver) StorageDriver {
+	if v, ok := annotations[HelmStorageDriverAnnotation]; ok && v != "" {
+		return StorageDriver(v)
+	}
+	return defaultDriver
+}